@@ -18,10 +18,16 @@ package utils
 
 import (
 	"io"
+	"sync"
 
 	"golang.org/x/crypto/ssh"
 )
 
+// DefaultRSAAlgPreference is the order in which RSA signature algorithms are
+// preferred when negotiating with a server that advertises the RFC 8332
+// "server-sig-algs" extension.
+var DefaultRSAAlgPreference = []string{ssh.SigAlgoRSASHA2512, ssh.SigAlgoRSASHA2256, ssh.SigAlgoRSA}
+
 // AlgSigner wraps a provided ssh.Signer to ensure signature algorithm
 // compatibility with OpenSSH.
 //
@@ -32,8 +38,9 @@ import (
 // If the provided Signer is not an RSA key or does not implement
 // ssh.AlgorithmSigner, it's returned as is.
 //
-// DELETE IN 5.0: assuming https://github.com/golang/go/issues/37278 is fixed
-// by then and we pull in the fix. Also delete all call sites.
+// Deprecated: use NegotiatingAlgSigner, which picks the strongest algorithm
+// from a list of algorithms the peer is known to accept instead of assuming
+// one up front.
 func AlgSigner(s ssh.Signer, alg string) ssh.Signer {
 	if alg == "" {
 		return s
@@ -66,3 +73,126 @@ func (s fixedAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, alg
 func (s fixedAlgorithmSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
 	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, s.alg)
 }
+
+// NegotiatingAlgSigner wraps a provided ssh.Signer to pick an RSA signature
+// algorithm from a list the caller already knows the peer accepts, instead
+// of assuming a single fixed algorithm.
+//
+// This is NOT needed for ordinary SSH public-key user authentication: when a
+// signer passed to ssh.PublicKeys implements ssh.AlgorithmSigner,
+// x/crypto/ssh negotiates rsa-sha2-256/512 automatically via the RFC 8332
+// "server-sig-algs" extension, and there is no supported hook to observe or
+// override that from outside the package. Use NegotiatingAlgSigner for
+// signing done outside of that handshake-driven path, where serverSigAlgs
+// can be answered from something already known out of band (e.g. the
+// algorithms a server's ssh.ServerConfig was configured with via
+// WireServerSigAlgs).
+//
+// On the first signature it consults serverSigAlgs for the list of
+// algorithms the peer is known to accept, picks the strongest one present in
+// both that list and pref, and caches the choice for subsequent signatures.
+// If serverSigAlgs returns nothing, it falls back to defaultAlg.
+//
+// If the provided Signer is not an RSA key or does not implement
+// ssh.AlgorithmSigner, it's returned as is.
+func NegotiatingAlgSigner(s ssh.Signer, defaultAlg string, pref []string, serverSigAlgs func() []string) ssh.Signer {
+	if s.PublicKey().Type() != ssh.KeyAlgoRSA && s.PublicKey().Type() != ssh.CertAlgoRSAv01 {
+		return s
+	}
+	as, ok := s.(ssh.AlgorithmSigner)
+	if !ok {
+		return s
+	}
+	if len(pref) == 0 {
+		pref = DefaultRSAAlgPreference
+	}
+	return &negotiatingAlgSigner{
+		AlgorithmSigner: as,
+		defaultAlg:      defaultAlg,
+		pref:            pref,
+		serverSigAlgs:   serverSigAlgs,
+	}
+}
+
+type negotiatingAlgSigner struct {
+	ssh.AlgorithmSigner
+
+	defaultAlg    string
+	pref          []string
+	serverSigAlgs func() []string
+
+	once sync.Once
+	alg  string
+}
+
+// resolve picks the algorithm to sign with, negotiating against the
+// server's advertised list the first time it's called and caching the
+// result for every call after that.
+func (s *negotiatingAlgSigner) resolve() string {
+	s.once.Do(func() {
+		s.alg = s.defaultAlg
+		if s.serverSigAlgs == nil {
+			return
+		}
+		advertised := s.serverSigAlgs()
+		if len(advertised) == 0 {
+			return
+		}
+		for _, want := range s.pref {
+			for _, have := range advertised {
+				if want == have {
+					s.alg = want
+					return
+				}
+			}
+		}
+	})
+	return s.alg
+}
+
+func (s *negotiatingAlgSigner) SignWithAlgorithm(rand io.Reader, data []byte, alg string) (*ssh.Signature, error) {
+	if alg == "" {
+		alg = s.resolve()
+	}
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, alg)
+}
+
+func (s *negotiatingAlgSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, s.resolve())
+}
+
+// ServerSigAlgsExtension builds a "server-sig-algs" extension-info payload
+// listing algs (or DefaultRSAAlgPreference, if empty) as a comma-separated
+// name-list. Per RFC 8332 this is sent as part of the SSH_MSG_EXT_INFO
+// message exchanged right after SSH_MSG_NEWKEYS (not a global request), so
+// clients can pick a stronger algorithm for RSA host keys and user
+// certificate challenges instead of assuming "ssh-rsa". It only builds the
+// payload; nothing in this package sends it.
+func ServerSigAlgsExtension(algs []string) map[string][]byte {
+	if len(algs) == 0 {
+		algs = DefaultRSAAlgPreference
+	}
+	joined := algs[0]
+	for _, alg := range algs[1:] {
+		joined += "," + alg
+	}
+	return map[string][]byte{
+		"server-sig-algs": []byte(joined),
+	}
+}
+
+// WireServerSigAlgs configures cfg to accept algs (or DefaultRSAAlgPreference,
+// if empty) as the public key algorithms it accepts from connecting clients,
+// via ServerConfig.PublicKeyAuthAlgorithms. x/crypto/ssh uses that list both
+// to decide which signature algorithms it accepts during public key
+// authentication and, if the client supports it, to build the RFC 8332
+// "server-sig-algs" extension-info it sends, so RSA client keys aren't
+// limited to the legacy "ssh-rsa"/SHA-1 signature. It returns the resolved
+// list that was set.
+func WireServerSigAlgs(cfg *ssh.ServerConfig, algs []string) []string {
+	if len(algs) == 0 {
+		algs = DefaultRSAAlgPreference
+	}
+	cfg.PublicKeyAuthAlgorithms = algs
+	return algs
+}
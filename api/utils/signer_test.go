@@ -0,0 +1,163 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestRSASigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+func TestNegotiatingAlgSignerPicksStrongestAdvertised(t *testing.T) {
+	calls := 0
+	signer := NegotiatingAlgSigner(newTestRSASigner(t), ssh.SigAlgoRSA, DefaultRSAAlgPreference, func() []string {
+		calls++
+		if calls > 1 {
+			return []string{ssh.SigAlgoRSASHA2512}
+		}
+		return []string{ssh.SigAlgoRSA, ssh.SigAlgoRSASHA2256}
+	})
+	alg := signer.(*negotiatingAlgSigner).resolve()
+	require.Equal(t, ssh.SigAlgoRSASHA2256, alg)
+
+	// Resolution is cached even if the server list would change afterwards.
+	alg2 := signer.(*negotiatingAlgSigner).resolve()
+	require.Equal(t, alg, alg2)
+}
+
+func TestNegotiatingAlgSignerFallsBackWithoutExtension(t *testing.T) {
+	signer := NegotiatingAlgSigner(newTestRSASigner(t), ssh.SigAlgoRSA, DefaultRSAAlgPreference, func() []string {
+		return nil
+	})
+	require.Equal(t, ssh.SigAlgoRSA, signer.(*negotiatingAlgSigner).resolve())
+}
+
+func TestNegotiatingAlgSignerIgnoresNonRSAKeys(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromSigner(priv)
+	require.NoError(t, err)
+
+	wrapped := NegotiatingAlgSigner(signer, "", nil, func() []string { return nil })
+	require.Equal(t, signer, wrapped)
+}
+
+func TestServerSigAlgsExtensionPayload(t *testing.T) {
+	ext := ServerSigAlgsExtension([]string{ssh.SigAlgoRSASHA2512, ssh.SigAlgoRSASHA2256})
+	require.Equal(t, []byte("rsa-sha2-512,rsa-sha2-256"), ext["server-sig-algs"])
+
+	// Empty input falls back to DefaultRSAAlgPreference.
+	def := ServerSigAlgsExtension(nil)
+	require.Contains(t, string(def["server-sig-algs"]), ssh.SigAlgoRSASHA2512)
+}
+
+func TestWireServerSigAlgsSetsAcceptedAlgorithms(t *testing.T) {
+	cfg := &ssh.ServerConfig{}
+	got := WireServerSigAlgs(cfg, nil)
+
+	require.Equal(t, DefaultRSAAlgPreference, got)
+	require.Equal(t, DefaultRSAAlgPreference, cfg.PublicKeyAuthAlgorithms)
+}
+
+// algSpy wraps an ssh.AlgorithmSigner and records the algorithm x/crypto/ssh
+// actually chose to sign with, so a test can observe what a real handshake
+// negotiated.
+type algSpy struct {
+	ssh.AlgorithmSigner
+
+	mu   sync.Mutex
+	algs []string
+}
+
+func (s *algSpy) SignWithAlgorithm(rand io.Reader, data []byte, alg string) (*ssh.Signature, error) {
+	s.mu.Lock()
+	s.algs = append(s.algs, alg)
+	s.mu.Unlock()
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, alg)
+}
+
+func (s *algSpy) last() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.algs) == 0 {
+		return ""
+	}
+	return s.algs[len(s.algs)-1]
+}
+
+// TestWireServerSigAlgsNegotiatesOverRealHandshake proves, end to end against
+// a real client<->server SSH handshake, that configuring a server with
+// WireServerSigAlgs causes x/crypto/ssh to negotiate a SHA-2 RSA signature
+// algorithm for client public-key authentication instead of the legacy
+// "ssh-rsa"/SHA-1 one.
+func TestWireServerSigAlgsNegotiatesOverRealHandshake(t *testing.T) {
+	hostSigner := newTestRSASigner(t)
+
+	serverCfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	WireServerSigAlgs(serverCfg, DefaultRSAAlgPreference)
+	serverCfg.AddHostKey(hostSigner)
+
+	clientRawSigner := newTestRSASigner(t)
+	spy := &algSpy{AlgorithmSigner: clientRawSigner.(ssh.AlgorithmSigner)}
+
+	clientCfg := &ssh.ClientConfig{
+		User:            "alice",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(spy)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	serverErrC := make(chan error, 1)
+	go func() {
+		_, _, _, err := ssh.NewServerConn(serverConn, serverCfg)
+		serverErrC <- err
+	}()
+
+	_, _, _, err := ssh.NewClientConn(clientConn, "pipe", clientCfg)
+	require.NoError(t, err)
+	require.NoError(t, <-serverErrC)
+
+	negotiated := spy.last()
+	require.NotEmpty(t, negotiated, "expected the client signer to be used during authentication")
+	require.NotEqual(t, ssh.SigAlgoRSA, negotiated, "server advertised SHA-2 support, so the legacy ssh-rsa/SHA-1 algorithm should not have been negotiated")
+	require.Contains(t, DefaultRSAAlgPreference, negotiated)
+}
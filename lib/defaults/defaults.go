@@ -0,0 +1,30 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package defaults holds default values shared across Teleport subsystems.
+package defaults
+
+import "time"
+
+const (
+	// Namespace is the default namespace used when one is not specified.
+	Namespace = "default"
+
+	// RegistryAuthTokenTTL is the default lifetime of a Docker Registry v2
+	// bearer token minted by lib/registry/tokenserver when the cluster's
+	// RegistryAuthConfig does not specify one.
+	RegistryAuthTokenTTL = 5 * time.Minute
+)
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"github.com/gravitational/trace"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// SignDetached returns a detached compact-serialization JWS covering data,
+// signed by this Key. Unlike SignRegistryAccessToken/SignIdentityToken, the
+// payload is opaque bytes rather than a JWT claim set, for callers that only
+// need a tamper-evident signature over an arbitrary blob, e.g. a serialized
+// resource snapshot.
+func (k *Key) SignDetached(data []byte) (string, error) {
+	sig, err := k.signer.Sign(data)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	jws, err := sig.DetachedCompactSerialize()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return jws, nil
+}
+
+// VerifyDetached checks that jws is a valid detached compact-serialization
+// JWS over data, produced by this Key.
+func (k *Key) VerifyDetached(data []byte, jws string) error {
+	sig, err := jose.ParseDetached(jws, data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := sig.Verify(k.PublicKey()); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"time"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// identityClaims is the claim set used for short-lived identity tokens, e.g.
+// those an OIDC/JWT provisioner hands a registry client so it can prove its
+// Teleport roles without presenting a client certificate.
+type identityClaims struct {
+	jwt.Claims
+	Roles []string `json:"roles"`
+}
+
+// SignIdentityToken mints a JWT asserting subject holds roles, valid from
+// issuedAt until expiresAt.
+func (k *Key) SignIdentityToken(subject string, roles []string, issuedAt, expiresAt time.Time) (string, error) {
+	if subject == "" {
+		return "", trace.BadParameter("jwt: subject is required")
+	}
+	claims := identityClaims{
+		Claims: jwt.Claims{
+			Issuer:    k.cfg.ClusterName,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			Expiry:    jwt.NewNumericDate(expiresAt),
+		},
+		Roles: roles,
+	}
+	token, err := jwt.Signed(k.signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return token, nil
+}
+
+// VerifyIdentityToken verifies a JWT minted by SignIdentityToken and returns
+// the subject and roles it asserts.
+func (k *Key) VerifyIdentityToken(token string) (subject string, roles []string, err error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return "", nil, trace.Wrap(err)
+	}
+
+	var claims identityClaims
+	if err := parsed.Claims(k.PublicKey(), &claims); err != nil {
+		return "", nil, trace.Wrap(err)
+	}
+	if err := claims.Validate(jwt.Expected{Time: time.Now()}); err != nil {
+		return "", nil, trace.Wrap(err)
+	}
+	return claims.Subject, claims.Roles, nil
+}
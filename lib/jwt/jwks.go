@@ -0,0 +1,67 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"bytes"
+
+	"github.com/gravitational/trace"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// JWKS is a JSON Web Key Set, as advertised by a cluster's
+// /.well-known/jwks.json endpoint, used to verify signatures produced by a
+// peer cluster's JWT CA without sharing the private key.
+type JWKS struct {
+	Keys []jose.JSONWebKey `json:"keys"`
+}
+
+// VerifyJWKS checks that jws is a valid detached compact-serialization JWS
+// over data, produced by one of the keys in jwks. It returns an error if no
+// key in the set produces a valid signature.
+func VerifyJWKS(jwks JWKS, data []byte, jws string) error {
+	if len(jwks.Keys) == 0 {
+		return trace.BadParameter("jwt: JWKS has no keys")
+	}
+
+	sig, err := jose.ParseDetached(jws, data)
+	if err != nil {
+		parsed, perr := jose.ParseSigned(jws)
+		if perr != nil {
+			return trace.Wrap(err)
+		}
+		sig = parsed
+	}
+
+	var lastErr error
+	for _, key := range jwks.Keys {
+		payload, err := sig.Verify(key.Public())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(data) > 0 && !bytes.Equal(payload, data) {
+			lastErr = trace.BadParameter("jwt: signature payload does not match data")
+			continue
+		}
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = trace.AccessDenied("jwt: no key in JWKS verified the signature")
+	}
+	return trace.Wrap(lastErr)
+}
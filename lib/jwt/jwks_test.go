@@ -0,0 +1,73 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func signDetached(t *testing.T, key *rsa.PrivateKey, data []byte) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	require.NoError(t, err)
+	sig, err := signer.Sign(data)
+	require.NoError(t, err)
+	jws, err := sig.DetachedCompactSerialize()
+	require.NoError(t, err)
+	return jws
+}
+
+func TestVerifyJWKSRoundTrip(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data := []byte(`{"cluster_name":"leaf"}`)
+	jws := signDetached(t, key, data)
+
+	jwks := JWKS{Keys: []jose.JSONWebKey{{Key: key.Public()}}}
+	require.NoError(t, VerifyJWKS(jwks, data, jws))
+}
+
+func TestVerifyJWKSRejectsTamperedData(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data := []byte(`{"cluster_name":"leaf"}`)
+	jws := signDetached(t, key, data)
+
+	jwks := JWKS{Keys: []jose.JSONWebKey{{Key: key.Public()}}}
+	require.Error(t, VerifyJWKS(jwks, []byte(`{"cluster_name":"tampered"}`), jws))
+}
+
+func TestVerifyJWKSRejectsWrongKey(t *testing.T) {
+	signing, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data := []byte(`{"cluster_name":"leaf"}`)
+	jws := signDetached(t, signing, data)
+
+	jwks := JWKS{Keys: []jose.JSONWebKey{{Key: other.Public()}}}
+	require.Error(t, VerifyJWKS(jwks, data, jws))
+}
+
+func TestVerifyJWKSRequiresKeys(t *testing.T) {
+	require.Error(t, VerifyJWKS(JWKS{}, []byte("data"), "not-checked"))
+}
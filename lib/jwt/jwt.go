@@ -0,0 +1,90 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jwt signs and verifies JWTs issued by the cluster's JWT CA, for
+// use by subsystems that need to hand out short-lived, cluster-signed
+// tokens to external systems (Docker Registry v2 bearer tokens, trusted
+// cluster configuration snapshots, application access, etc).
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+
+	"github.com/gravitational/trace"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// Config configures a Key.
+type Config struct {
+	// ClusterName is the name of the cluster that owns the signing key,
+	// used as the default issuer for minted tokens.
+	ClusterName string
+	// PrivateKey is used to sign tokens. Required.
+	PrivateKey crypto.Signer
+	// Algorithm is the JOSE signature algorithm to sign with. Defaults to
+	// RS256 for RSA keys and ES256 for EC keys.
+	Algorithm jose.SignatureAlgorithm
+}
+
+// CheckAndSetDefaults validates the config and sets default values.
+func (c *Config) CheckAndSetDefaults() error {
+	if c.PrivateKey == nil {
+		return trace.BadParameter("jwt: PrivateKey is required")
+	}
+	if c.Algorithm == "" {
+		switch c.PrivateKey.Public().(type) {
+		case *ecdsa.PublicKey:
+			c.Algorithm = jose.ES256
+		default:
+			c.Algorithm = jose.RS256
+		}
+	}
+	return nil
+}
+
+// Key signs and verifies JWTs on behalf of the cluster's JWT CA.
+type Key struct {
+	cfg    Config
+	signer jose.Signer
+}
+
+// New returns a new Key.
+func New(cfg Config) (*Key, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	kid, err := LibtrustKeyID(cfg.PrivateKey.Public())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: cfg.Algorithm,
+		Key:       cfg.PrivateKey,
+	}, (&jose.SignerOptions{}).WithHeader("kid", kid).WithType("JWT"))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return &Key{cfg: cfg, signer: signer}, nil
+}
+
+// PublicKey returns the public half of the signing key.
+func (k *Key) PublicKey() crypto.PublicKey {
+	return k.cfg.PrivateKey.Public()
+}
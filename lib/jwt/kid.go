@@ -0,0 +1,53 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+
+	"github.com/gravitational/trace"
+)
+
+// LibtrustKeyID computes the "kid" Docker Distribution expects in the
+// header of a registry bearer token: the same libtrust-style JWK thumbprint
+// docker/libtrust derives from a key's DER-encoded SubjectPublicKeyInfo --
+// the first 240 bits of its SHA-256 digest, base32-encoded and grouped into
+// 12 colon-separated blocks of 4 characters, e.g.
+// "ABCD:EFGH:IJKL:MNOP:QRST:UVWX:YZ23:4567:ABCD:EFGH:IJKL:MNOP".
+func LibtrustKeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	digest := sha256.Sum256(der)
+	encoded := base32.StdEncoding.EncodeToString(digest[:30]) // first 240 bits
+
+	const groupSize = 4
+	groups := len(encoded) / groupSize
+	out := make([]byte, 0, len(encoded)+groups-1)
+	for i := 0; i < groups; i++ {
+		if i > 0 {
+			out = append(out, ':')
+		}
+		out = append(out, encoded[i*groupSize:(i+1)*groupSize]...)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibtrustKeyID(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	kid, err := LibtrustKeyID(key.Public())
+	require.NoError(t, err)
+
+	groups := strings.Split(kid, ":")
+	require.Len(t, groups, 12)
+	for _, g := range groups {
+		require.Len(t, g, 4)
+	}
+
+	// Deterministic for the same key.
+	kid2, err := LibtrustKeyID(key.Public())
+	require.NoError(t, err)
+	require.Equal(t, kid, kid2)
+
+	// Different for a different key.
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	kidOther, err := LibtrustKeyID(other.Public())
+	require.NoError(t, err)
+	require.NotEqual(t, kid, kidOther)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokenserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/teleport/lib/jwt"
+	"github.com/gravitational/teleport/lib/tlsca"
+
+	"github.com/gravitational/trace"
+)
+
+// CertAuthenticator authenticates callers presenting a Teleport user
+// certificate over mutual TLS, as used by `docker login`/`docker pull`
+// clients configured to trust the cluster's host CA.
+type CertAuthenticator struct{}
+
+// Authenticate extracts the Teleport identity from the client certificate
+// presented on the TLS connection.
+func (CertAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, trace.AccessDenied("registry auth requires a client certificate")
+	}
+	cert := r.TLS.PeerCertificates[0]
+	id, err := tlsca.FromSubject(cert.Subject, cert.NotAfter)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if id.Username == "" {
+		return nil, trace.AccessDenied("client certificate does not carry a Teleport identity")
+	}
+	return &Identity{Subject: id.Username, Roles: id.Groups}, nil
+}
+
+// JWTAuthenticator authenticates callers presenting an
+// "Authorization: Bearer <jwt>" header, as issued by an OIDC connector or
+// Teleport's own JWT CA acting as a provisioner for non-interactive
+// clients (e.g. CI pipelines) that cannot hold a user certificate.
+type JWTAuthenticator struct {
+	// Key verifies the bearer token and extracts the identity it asserts.
+	Key *jwt.Key
+}
+
+// Authenticate verifies the bearer JWT on the request and returns the
+// identity it asserts.
+func (a JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return nil, trace.AccessDenied("registry auth requires a bearer token")
+	}
+	if a.Key == nil {
+		return nil, trace.BadParameter("tokenserver: JWTAuthenticator.Key is required")
+	}
+
+	token := strings.TrimPrefix(header, prefix)
+	subject, roles, err := a.Key.VerifyIdentityToken(token)
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid bearer token")
+	}
+	return &Identity{Subject: subject, Roles: roles}, nil
+}
+
+// ChainAuthenticator tries each Authenticator in order and returns the
+// first identity successfully extracted, allowing the /auth endpoint to
+// accept either a Teleport user cert or an OIDC/JWT provisioner token.
+type ChainAuthenticator []Authenticator
+
+// Authenticate tries each Authenticator in order.
+func (c ChainAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	var lastErr error
+	for _, a := range c {
+		id, err := a.Authenticate(r)
+		if err == nil {
+			return id, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = trace.AccessDenied("no authenticator configured")
+	}
+	return nil, trace.Wrap(lastErr)
+}
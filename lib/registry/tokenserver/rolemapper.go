@@ -0,0 +1,101 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokenserver
+
+import (
+	"path"
+
+	"github.com/gravitational/trace"
+)
+
+// RepositoryAccess is the access a single Teleport role grants to Docker
+// repositories, as returned by a RoleResolver.
+type RepositoryAccess struct {
+	// Repositories are glob patterns (e.g. "myorg/*") matched against the
+	// requested repository name.
+	Repositories []string
+	// Actions are the docker actions (e.g. "pull", "push") granted for
+	// repositories matching Repositories.
+	Actions []string
+}
+
+// RoleResolver resolves a set of Teleport role names to the repository
+// access they grant. This package provides no implementation backed by
+// Teleport roles; callers supply one, and tests supply a stub.
+type RoleResolver interface {
+	// GetRepositoryAccess returns the repository access granted by each of
+	// the named roles.
+	GetRepositoryAccess(roleNames []string) ([]RepositoryAccess, error)
+}
+
+// TeleportRoleMapper is the production RoleMapper, resolving requested
+// scopes against Teleport RBAC roles by matching the requested repository
+// name against each granted role's allowed repository patterns.
+type TeleportRoleMapper struct {
+	// Roles resolves role names to the repository access they grant.
+	Roles RoleResolver
+}
+
+// ResolveAccess filters requested down to the repositories and actions
+// granted by roles.
+func (m TeleportRoleMapper) ResolveAccess(roles []string, requested []Access) ([]Access, error) {
+	if m.Roles == nil {
+		return nil, trace.BadParameter("tokenserver: TeleportRoleMapper.Roles is required")
+	}
+	grants, err := m.Roles.GetRepositoryAccess(roles)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	granted := make([]Access, 0, len(requested))
+	for _, req := range requested {
+		allowed := allowedActions(grants, req.Name)
+		if len(allowed) == 0 {
+			continue
+		}
+		actions := make([]string, 0, len(req.Actions))
+		for _, action := range req.Actions {
+			if allowed[action] {
+				actions = append(actions, action)
+			}
+		}
+		if len(actions) == 0 {
+			continue
+		}
+		granted = append(granted, Access{Type: req.Type, Name: req.Name, Actions: actions})
+	}
+	return granted, nil
+}
+
+// allowedActions returns the set of actions granted to repository by any of
+// grants whose Repositories pattern matches it.
+func allowedActions(grants []RepositoryAccess, repository string) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, grant := range grants {
+		for _, pattern := range grant.Repositories {
+			matched, err := path.Match(pattern, repository)
+			if err != nil || !matched {
+				continue
+			}
+			for _, action := range grant.Actions {
+				allowed[action] = true
+			}
+			break
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tokenserver implements a Docker Registry v2 bearer-token issuer
+// backed by Teleport identity and RBAC, following the token authentication
+// handshake described in
+// https://docs.docker.com/registry/spec/auth/token/
+package tokenserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gravitational/teleport/lib/jwt"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/gravitational/roundtrip"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Access describes a single requested scope, e.g.
+// "repository:samalba/my-app:pull,push".
+type Access struct {
+	// Type is the resource type, almost always "repository".
+	Type string
+	// Name is the repository name being accessed.
+	Name string
+	// Actions is the set of requested actions, e.g. "pull" or "push".
+	Actions []string
+}
+
+// RoleMapper resolves the repositories and actions a Teleport identity is
+// allowed to access, given the roles attached to that identity.
+type RoleMapper interface {
+	// ResolveAccess filters requested down to the subset of repositories and
+	// actions the given roles grant.
+	ResolveAccess(roles []string, requested []Access) ([]Access, error)
+}
+
+// Identity is the caller identity extracted from an incoming auth request,
+// either a Teleport user cert or an OIDC/JWT provisioner token.
+type Identity struct {
+	// Subject is the identity's subject, e.g. a Teleport username.
+	Subject string
+	// Roles are the Teleport roles assigned to the identity.
+	Roles []string
+}
+
+// Authenticator verifies the credentials presented to the /auth endpoint and
+// returns the caller's identity.
+type Authenticator interface {
+	// Authenticate validates the incoming request and returns the caller's
+	// identity, or an error if the request could not be authenticated.
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Config configures a Server.
+type Config struct {
+	// Authenticator authenticates incoming /auth requests.
+	Authenticator Authenticator
+	// RoleMapper resolves requested scopes against Teleport RBAC.
+	RoleMapper RoleMapper
+	// Signer mints the JWTs returned to callers.
+	Signer *jwt.Key
+	// GetRegistryAuthConfig returns the current registry auth configuration.
+	GetRegistryAuthConfig func() (services.RegistryAuthConfig, error)
+	// Clock is used to compute token expiry. Defaults to the system clock.
+	Clock func() time.Time
+}
+
+// CheckAndSetDefaults validates the config and sets default values.
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Authenticator == nil {
+		return trace.BadParameter("tokenserver: Authenticator is required")
+	}
+	if c.RoleMapper == nil {
+		return trace.BadParameter("tokenserver: RoleMapper is required")
+	}
+	if c.Signer == nil {
+		return trace.BadParameter("tokenserver: Signer is required")
+	}
+	if c.GetRegistryAuthConfig == nil {
+		return trace.BadParameter("tokenserver: GetRegistryAuthConfig is required")
+	}
+	if c.Clock == nil {
+		c.Clock = time.Now
+	}
+	return nil
+}
+
+// Server implements the Docker Registry v2 token authentication handshake,
+// issuing short-lived JWTs scoped to the caller's Teleport roles.
+type Server struct {
+	cfg Config
+	log *logrus.Entry
+}
+
+// New returns a new Server.
+func New(cfg Config) (*Server, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Server{
+		cfg: cfg,
+		log: logrus.WithField(trace.Component, "registry:tokenserver"),
+	}, nil
+}
+
+// ServeHTTP implements the GET /auth handshake.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, err := s.issueToken(r)
+	if err != nil {
+		s.log.WithError(err).Warn("Failed to issue registry auth token.")
+		trace.WriteError(w, err)
+		return
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, map[string]interface{}{
+		"token":        token,
+		"access_token": token,
+	})
+}
+
+func (s *Server) issueToken(r *http.Request) (string, error) {
+	identity, err := s.cfg.Authenticator.Authenticate(r)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	authCfg, err := s.cfg.GetRegistryAuthConfig()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	requested := parseScopes(r.URL.Query()["scope"])
+	granted, err := s.cfg.RoleMapper.ResolveAccess(identity.Roles, requested)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	now := s.cfg.Clock()
+	token, err := s.cfg.Signer.SignRegistryAccessToken(jwt.RegistryAccessParams{
+		Issuer:       authCfg.GetIssuer(),
+		Service:      r.URL.Query().Get("service"),
+		Subject:      identity.Subject,
+		Access:       toJWTAccess(granted),
+		SetIssuedAt:  now,
+		SetExpiresAt: now.Add(authCfg.GetTokenTTL()),
+	})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return token, nil
+}
+
+// parseScopes parses the "scope" query parameters from a token request, each
+// of the form "repository:<name>:<action>[,<action>...]".
+func parseScopes(raw []string) []Access {
+	access := make([]Access, 0, len(raw))
+	for _, scope := range raw {
+		parts := strings.SplitN(scope, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		access = append(access, Access{
+			Type:    parts[0],
+			Name:    parts[1],
+			Actions: strings.Split(parts[2], ","),
+		})
+	}
+	return access
+}
+
+func toJWTAccess(access []Access) []jwt.RegistryAccessEntry {
+	out := make([]jwt.RegistryAccessEntry, 0, len(access))
+	for _, a := range access {
+		out = append(out, jwt.RegistryAccessEntry{
+			Type:    a.Type,
+			Name:    a.Name,
+			Actions: a.Actions,
+		})
+	}
+	return out
+}
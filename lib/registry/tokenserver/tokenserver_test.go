@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokenserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []string
+		want []Access
+	}{
+		{
+			name: "single scope, single action",
+			raw:  []string{"repository:samalba/my-app:pull"},
+			want: []Access{{Type: "repository", Name: "samalba/my-app", Actions: []string{"pull"}}},
+		},
+		{
+			name: "single scope, multiple actions",
+			raw:  []string{"repository:samalba/my-app:pull,push"},
+			want: []Access{{Type: "repository", Name: "samalba/my-app", Actions: []string{"pull", "push"}}},
+		},
+		{
+			name: "multiple scopes",
+			raw:  []string{"repository:foo/bar:pull", "repository:baz/qux:push"},
+			want: []Access{
+				{Type: "repository", Name: "foo/bar", Actions: []string{"pull"}},
+				{Type: "repository", Name: "baz/qux", Actions: []string{"push"}},
+			},
+		},
+		{
+			name: "malformed scope is skipped",
+			raw:  []string{"not-a-scope", "repository:foo/bar:pull"},
+			want: []Access{{Type: "repository", Name: "foo/bar", Actions: []string{"pull"}}},
+		},
+		{
+			name: "empty input",
+			raw:  nil,
+			want: []Access{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, parseScopes(tt.raw))
+		})
+	}
+}
+
+type stubRoleResolver struct {
+	grants []RepositoryAccess
+	err    error
+}
+
+func (s stubRoleResolver) GetRepositoryAccess(roleNames []string) ([]RepositoryAccess, error) {
+	return s.grants, s.err
+}
+
+func TestTeleportRoleMapperResolveAccess(t *testing.T) {
+	mapper := TeleportRoleMapper{
+		Roles: stubRoleResolver{
+			grants: []RepositoryAccess{
+				{Repositories: []string{"myorg/*"}, Actions: []string{"pull"}},
+				{Repositories: []string{"myorg/releases"}, Actions: []string{"pull", "push"}},
+			},
+		},
+	}
+
+	granted, err := mapper.ResolveAccess(nil, []Access{
+		{Type: "repository", Name: "myorg/releases", Actions: []string{"pull", "push"}},
+		{Type: "repository", Name: "myorg/dev", Actions: []string{"pull", "push"}},
+		{Type: "repository", Name: "otherorg/app", Actions: []string{"pull"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Access{
+		{Type: "repository", Name: "myorg/releases", Actions: []string{"pull", "push"}},
+		{Type: "repository", Name: "myorg/dev", Actions: []string{"pull"}},
+	}, granted)
+}
+
+func TestTeleportRoleMapperRequiresResolver(t *testing.T) {
+	var mapper TeleportRoleMapper
+	_, err := mapper.ResolveAccess(nil, []Access{{Type: "repository", Name: "a/b", Actions: []string{"pull"}}})
+	require.Error(t, err)
+}
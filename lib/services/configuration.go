@@ -0,0 +1,86 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import "context"
+
+// ClusterConfiguration is the interface to the storage backend for cluster
+// configuration-related resources, implemented by
+// lib/services/local.ClusterConfigurationService. It is the interface auth
+// server reconciliation loops depend on, so that they can retry on a
+// concurrent write instead of racing with other auth servers.
+type ClusterConfiguration interface {
+	// GetClusterName gets the name of the cluster from the backend.
+	GetClusterName(opts ...MarshalOption) (ClusterName, error)
+	// SetClusterName sets the name of the cluster in the backend. Can only
+	// be called once on a cluster after which it will return
+	// trace.AlreadyExists.
+	SetClusterName(ClusterName) error
+	// UpsertClusterName sets the name of the cluster in the backend.
+	UpsertClusterName(ClusterName) error
+	// UpdateClusterName updates the name of the cluster in the backend,
+	// failing if it has been concurrently modified since it was last read.
+	UpdateClusterName(ctx context.Context, c ClusterName) error
+	// DeleteClusterName deletes the cluster name from the backend.
+	DeleteClusterName() error
+
+	// GetStaticTokens gets the list of static tokens used to provision
+	// nodes.
+	GetStaticTokens() (StaticTokens, error)
+	// SetStaticTokens sets the list of static tokens used to provision
+	// nodes.
+	SetStaticTokens(StaticTokens) error
+	// UpdateStaticTokens updates the list of static tokens used to
+	// provision nodes, failing if it has been concurrently modified since
+	// it was last read.
+	UpdateStaticTokens(ctx context.Context, c StaticTokens) error
+	// DeleteStaticTokens deletes static tokens.
+	DeleteStaticTokens() error
+
+	// GetAuthPreference fetches the cluster authentication preferences.
+	GetAuthPreference() (AuthPreference, error)
+	// SetAuthPreference sets the cluster authentication preferences.
+	SetAuthPreference(AuthPreference) error
+	// UpdateAuthPreference updates the cluster authentication preferences,
+	// failing if they have been concurrently modified since they were last
+	// read.
+	UpdateAuthPreference(ctx context.Context, preferences AuthPreference) error
+
+	// GetClusterConfig gets the cluster configuration.
+	GetClusterConfig(opts ...MarshalOption) (ClusterConfig, error)
+	// SetClusterConfig sets the cluster configuration.
+	SetClusterConfig(ClusterConfig) error
+	// UpdateClusterConfig updates the cluster configuration, failing if it
+	// has been concurrently modified since it was last read.
+	UpdateClusterConfig(ctx context.Context, cc ClusterConfig) error
+	// DeleteClusterConfig deletes the cluster configuration.
+	DeleteClusterConfig() error
+
+	// GetRegistryAuthConfig fetches the Docker Registry v2 token issuer
+	// configuration.
+	GetRegistryAuthConfig() (RegistryAuthConfig, error)
+	// SetRegistryAuthConfig sets the Docker Registry v2 token issuer
+	// configuration.
+	SetRegistryAuthConfig(RegistryAuthConfig) error
+	// DeleteRegistryAuthConfig deletes the Docker Registry v2 token issuer
+	// configuration.
+	DeleteRegistryAuthConfig() error
+	// CompareAndSwapRegistryAuthConfig updates the Docker Registry v2 token
+	// issuer configuration, failing if the stored value does not match
+	// expected.
+	CompareAndSwapRegistryAuthConfig(ctx context.Context, new, expected RegistryAuthConfig) error
+}
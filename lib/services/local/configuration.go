@@ -25,16 +25,28 @@ import (
 	"github.com/gravitational/trace"
 )
 
+// compile-time check that ClusterConfigurationService implements
+// services.ClusterConfiguration.
+var _ services.ClusterConfiguration = (*ClusterConfigurationService)(nil)
+
 // ClusterConfigurationService is responsible for managing cluster configuration.
 type ClusterConfigurationService struct {
 	backend.Backend
+
+	// signer, if set, produces detached JOSE signatures for every stored
+	// configuration resource. See ClusterConfigSigner.
+	signer ClusterConfigSigner
 }
 
 // NewClusterConfigurationService returns a new ClusterConfigurationService.
-func NewClusterConfigurationService(backend backend.Backend) *ClusterConfigurationService {
-	return &ClusterConfigurationService{
+func NewClusterConfigurationService(backend backend.Backend, opts ...ServiceOption) *ClusterConfigurationService {
+	s := &ClusterConfigurationService{
 		Backend: backend,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // GetClusterName gets the name of the cluster from the backend.
@@ -102,6 +114,40 @@ func (s *ClusterConfigurationService) UpsertClusterName(c services.ClusterName)
 	return nil
 }
 
+// UpdateClusterName updates services.ClusterName in the backend, failing if
+// it has been concurrently modified by another client since it was last read.
+func (s *ClusterConfigurationService) UpdateClusterName(ctx context.Context, c services.ClusterName) error {
+	if err := c.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	existingItem, err := s.Get(ctx, backend.Key(clusterConfigPrefix, namePrefix))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("cluster name not found")
+		}
+		return trace.Wrap(err)
+	}
+
+	value, err := services.GetClusterNameMarshaler().Marshal(c)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updateItem := backend.Item{
+		Key:     backend.Key(clusterConfigPrefix, namePrefix),
+		Value:   value,
+		Expires: c.Expiry(),
+	}
+
+	_, err = s.CompareAndSwap(ctx, *existingItem, updateItem)
+	if err != nil {
+		if trace.IsCompareFailed(err) {
+			return trace.CompareFailed("cluster name has been modified by another client, try again")
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // GetStaticTokens gets the list of static tokens used to provision nodes.
 func (s *ClusterConfigurationService) GetStaticTokens() (services.StaticTokens, error) {
 	item, err := s.Get(context.TODO(), backend.Key(clusterConfigPrefix, staticTokensPrefix))
@@ -121,22 +167,53 @@ func (s *ClusterConfigurationService) SetStaticTokens(c services.StaticTokens) e
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	_, err = s.Put(context.TODO(), backend.Item{
+	return s.putSigned(context.TODO(), backend.Item{
 		Key:     backend.Key(clusterConfigPrefix, staticTokensPrefix),
 		Value:   value,
 		Expires: c.Expiry(),
 		ID:      c.GetResourceID(),
-	})
+	}, clusterConfigPrefix, staticTokensPrefix)
+}
+
+// UpdateStaticTokens updates the list of static tokens in the backend,
+// failing if it has been concurrently modified by another client since it
+// was last read.
+func (s *ClusterConfigurationService) UpdateStaticTokens(ctx context.Context, c services.StaticTokens) error {
+	if err := c.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	existingItem, err := s.Get(ctx, backend.Key(clusterConfigPrefix, staticTokensPrefix))
 	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("static tokens not found")
+		}
 		return trace.Wrap(err)
 	}
 
+	value, err := services.GetStaticTokensMarshaler().Marshal(c)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updateItem := backend.Item{
+		Key:     backend.Key(clusterConfigPrefix, staticTokensPrefix),
+		Value:   value,
+		Expires: c.Expiry(),
+	}
+
+	err = s.compareAndSwapSigned(ctx, *existingItem, updateItem, clusterConfigPrefix, staticTokensPrefix)
+	if err != nil {
+		if trace.IsCompareFailed(err) {
+			return trace.CompareFailed("static tokens have been modified by another client, try again")
+		}
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
 // DeleteStaticTokens deletes static tokens
 func (s *ClusterConfigurationService) DeleteStaticTokens() error {
-	err := s.Delete(context.TODO(), backend.Key(clusterConfigPrefix, staticTokensPrefix))
+	err := s.deleteSigned(context.TODO(), backend.Key(clusterConfigPrefix, staticTokensPrefix),
+		clusterConfigPrefix, staticTokensPrefix)
 	if err != nil {
 		if trace.IsNotFound(err) {
 			return trace.NotFound("static tokens are not found")
@@ -174,11 +251,40 @@ func (s *ClusterConfigurationService) SetAuthPreference(preferences services.Aut
 		ID:    preferences.GetResourceID(),
 	}
 
-	_, err = s.Put(context.TODO(), item)
+	return s.putSigned(context.TODO(), item, authPrefix, preferencePrefix)
+}
+
+// UpdateAuthPreference updates the cluster authentication preferences in the
+// backend, failing if it has been concurrently modified by another client
+// since it was last read.
+func (s *ClusterConfigurationService) UpdateAuthPreference(ctx context.Context, preferences services.AuthPreference) error {
+	if err := preferences.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	existingItem, err := s.Get(ctx, backend.Key(authPrefix, preferencePrefix, generalPrefix))
 	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("authentication preference not found")
+		}
 		return trace.Wrap(err)
 	}
 
+	value, err := services.GetAuthPreferenceMarshaler().Marshal(preferences)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updateItem := backend.Item{
+		Key:   backend.Key(authPrefix, preferencePrefix, generalPrefix),
+		Value: value,
+	}
+
+	err = s.compareAndSwapSigned(ctx, *existingItem, updateItem, authPrefix, preferencePrefix)
+	if err != nil {
+		if trace.IsCompareFailed(err) {
+			return trace.CompareFailed("authentication preference has been modified by another client, try again")
+		}
+		return trace.Wrap(err)
+	}
 	return nil
 }
 
@@ -209,7 +315,7 @@ func (s *ClusterConfigurationService) UpdateClusterConfig(ctx context.Context, c
 		Expires: update.Expiry(),
 	}
 
-	_, err = s.CompareAndSwap(ctx, *existingItem, updateItem)
+	err = s.compareAndSwapSigned(ctx, *existingItem, updateItem, clusterConfigPrefix, generalPrefix)
 	if err != nil {
 		if trace.IsCompareFailed(err) {
 			return trace.CompareFailed("cluster configuration has been updated by another client, try again")
@@ -244,7 +350,8 @@ func (s *ClusterConfigurationService) getClusterConfig(ctx context.Context, opts
 
 // DeleteClusterConfig deletes services.ClusterConfig from the backend.
 func (s *ClusterConfigurationService) DeleteClusterConfig() error {
-	err := s.Delete(context.TODO(), backend.Key(clusterConfigPrefix, generalPrefix))
+	err := s.deleteSigned(context.TODO(), backend.Key(clusterConfigPrefix, generalPrefix),
+		clusterConfigPrefix, generalPrefix)
 	if err != nil {
 		if trace.IsNotFound(err) {
 			return trace.NotFound("cluster configuration not found")
@@ -267,6 +374,38 @@ func (s *ClusterConfigurationService) SetClusterConfig(c services.ClusterConfig)
 		ID:    c.GetResourceID(),
 	}
 
+	return s.putSigned(context.TODO(), item, clusterConfigPrefix, generalPrefix)
+}
+
+// GetRegistryAuthConfig fetches the Docker Registry v2 token issuer
+// configuration from the backend.
+func (s *ClusterConfigurationService) GetRegistryAuthConfig() (services.RegistryAuthConfig, error) {
+	item, err := s.Get(context.TODO(), backend.Key(registryAuthPrefix, generalPrefix))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("registry auth config not found")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return services.GetRegistryAuthConfigMarshaler().Unmarshal(item.Value,
+		services.WithResourceID(item.ID), services.WithExpires(item.Expires))
+}
+
+// SetRegistryAuthConfig sets the Docker Registry v2 token issuer
+// configuration on the backend.
+func (s *ClusterConfigurationService) SetRegistryAuthConfig(rac services.RegistryAuthConfig) error {
+	value, err := services.GetRegistryAuthConfigMarshaler().Marshal(rac)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	item := backend.Item{
+		Key:     backend.Key(registryAuthPrefix, generalPrefix),
+		Value:   value,
+		Expires: rac.Expiry(),
+		ID:      rac.GetResourceID(),
+	}
+
 	_, err = s.Put(context.TODO(), item)
 	if err != nil {
 		return trace.Wrap(err)
@@ -275,6 +414,51 @@ func (s *ClusterConfigurationService) SetClusterConfig(c services.ClusterConfig)
 	return nil
 }
 
+// DeleteRegistryAuthConfig deletes the registry auth config from the backend.
+func (s *ClusterConfigurationService) DeleteRegistryAuthConfig() error {
+	err := s.Delete(context.TODO(), backend.Key(registryAuthPrefix, generalPrefix))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("registry auth config not found")
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// CompareAndSwapRegistryAuthConfig updates the registry auth config in the
+// backend, failing if the stored value does not match expected.
+func (s *ClusterConfigurationService) CompareAndSwapRegistryAuthConfig(ctx context.Context, new, expected services.RegistryAuthConfig) error {
+	newValue, err := services.GetRegistryAuthConfigMarshaler().Marshal(new)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	expectedValue, err := services.GetRegistryAuthConfigMarshaler().Marshal(expected)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = s.CompareAndSwap(ctx,
+		backend.Item{
+			Key:   backend.Key(registryAuthPrefix, generalPrefix),
+			Value: expectedValue,
+		},
+		backend.Item{
+			Key:     backend.Key(registryAuthPrefix, generalPrefix),
+			Value:   newValue,
+			Expires: new.Expiry(),
+			ID:      new.GetResourceID(),
+		},
+	)
+	if err != nil {
+		if trace.IsCompareFailed(err) {
+			return trace.CompareFailed("registry auth config has been updated by another client, try again")
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 const (
 	clusterConfigPrefix = "cluster_configuration"
 	namePrefix          = "name"
@@ -282,4 +466,5 @@ const (
 	authPrefix          = "authentication"
 	preferencePrefix    = "preference"
 	generalPrefix       = "general"
+	registryAuthPrefix  = "registry_auth"
 )
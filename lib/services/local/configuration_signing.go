@@ -0,0 +1,211 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/jwt"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/gravitational/trace"
+)
+
+// ClusterConfigSigner produces and verifies the detached JOSE signatures
+// ClusterConfigurationService attaches to the configuration resources it
+// stores, so trusted clusters can prove a snapshot was not tampered with in
+// transit.
+type ClusterConfigSigner interface {
+	// SignSnapshot returns a compact-serialization JWS covering data, signed
+	// by the cluster's JWT CA.
+	SignSnapshot(data []byte) (jws string, err error)
+	// VerifySnapshot checks that jws is a valid signature over data,
+	// produced by the cluster's JWT CA.
+	VerifySnapshot(data []byte, jws string) error
+}
+
+// WithClusterConfigSigner configures the ClusterConfigurationService to
+// sign every SetClusterConfig/SetAuthPreference/SetStaticTokens call with
+// signer, storing the resulting JWS alongside the resource.
+func WithClusterConfigSigner(signer ClusterConfigSigner) ServiceOption {
+	return func(s *ClusterConfigurationService) {
+		s.signer = signer
+	}
+}
+
+// ServiceOption configures optional behavior of a ClusterConfigurationService.
+type ServiceOption func(*ClusterConfigurationService)
+
+// putSigned writes item to the backend and, if a ClusterConfigSigner is
+// configured, also writes a detached JWS of item.Value under the parallel
+// "signatures/<name>" key alongside item.Key's prefix.
+func (s *ClusterConfigurationService) putSigned(ctx context.Context, item backend.Item, prefix, name string) error {
+	if _, err := s.Put(ctx, item); err != nil {
+		return trace.Wrap(err)
+	}
+	if s.signer == nil {
+		return nil
+	}
+	jws, err := s.signer.SignSnapshot(item.Value)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = s.Put(ctx, backend.Item{
+		Key:   backend.Key(prefix, signaturesPrefix, name),
+		Value: []byte(jws),
+	})
+	return trace.Wrap(err)
+}
+
+// compareAndSwapSigned performs a CompareAndSwap from existing to updateItem
+// and, if a ClusterConfigSigner is configured, re-signs updateItem's value
+// and stores the refreshed JWS alongside it. Without this, a CAS-based
+// update would leave the signature stored by putSigned covering the
+// resource's previous value, so VerifySignedClusterConfig would fail on a
+// perfectly valid, freshly-updated resource.
+func (s *ClusterConfigurationService) compareAndSwapSigned(ctx context.Context, existing, updateItem backend.Item, prefix, name string) error {
+	if _, err := s.CompareAndSwap(ctx, existing, updateItem); err != nil {
+		return err
+	}
+	if s.signer == nil {
+		return nil
+	}
+	jws, err := s.signer.SignSnapshot(updateItem.Value)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = s.Put(ctx, backend.Item{
+		Key:   backend.Key(prefix, signaturesPrefix, name),
+		Value: []byte(jws),
+	})
+	return trace.Wrap(err)
+}
+
+// deleteSigned deletes the resource at key and, if a ClusterConfigSigner is
+// configured, its parallel "signatures/<name>" JWS too, so a later reader
+// can never pair a still-present signature with a deleted (or subsequently
+// re-created without signing) resource.
+func (s *ClusterConfigurationService) deleteSigned(ctx context.Context, key []byte, prefix, name string) error {
+	if err := s.Delete(ctx, key); err != nil {
+		return trace.Wrap(err)
+	}
+	if s.signer == nil {
+		return nil
+	}
+	err := s.Delete(ctx, backend.Key(prefix, signaturesPrefix, name))
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetSignedClusterConfig returns the cluster configuration along with the
+// detached JWS stored for it, if any.
+func (s *ClusterConfigurationService) GetSignedClusterConfig(ctx context.Context) (services.ClusterConfig, string, error) {
+	_, cc, err := s.getClusterConfig(ctx)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	sigItem, err := s.Get(ctx, backend.Key(clusterConfigPrefix, signaturesPrefix, generalPrefix))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return cc, "", nil
+		}
+		return nil, "", trace.Wrap(err)
+	}
+	return cc, string(sigItem.Value), nil
+}
+
+// VerifySignedClusterConfig fetches the cluster configuration and its JWS
+// and verifies the signature against the configured ClusterConfigSigner,
+// returning the resource only if the signature is valid.
+func (s *ClusterConfigurationService) VerifySignedClusterConfig(ctx context.Context) (services.ClusterConfig, error) {
+	if s.signer == nil {
+		return nil, trace.BadParameter("no ClusterConfigSigner configured")
+	}
+	item, cc, err := s.getClusterConfig(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sigItem, err := s.Get(ctx, backend.Key(clusterConfigPrefix, signaturesPrefix, generalPrefix))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := s.signer.VerifySnapshot(item.Value, string(sigItem.Value)); err != nil {
+		return nil, trace.Wrap(err, "cluster configuration signature verification failed")
+	}
+	return cc, nil
+}
+
+// TrustedClusterConfigImporter validates and applies signed configuration
+// snapshots exported by a leaf cluster, using the leaf's advertised JWKS
+// rather than the importing cluster's own JWT CA.
+type TrustedClusterConfigImporter struct {
+	// ClusterConfigurationService is the backend the validated snapshot is
+	// applied to.
+	*ClusterConfigurationService
+}
+
+// NewTrustedClusterConfigImporter returns a new TrustedClusterConfigImporter
+// backed by svc.
+func NewTrustedClusterConfigImporter(svc *ClusterConfigurationService) *TrustedClusterConfigImporter {
+	return &TrustedClusterConfigImporter{ClusterConfigurationService: svc}
+}
+
+// Import validates a signed cluster configuration snapshot exported by a
+// leaf cluster against that leaf's JWKS and, if valid, stores it under a
+// key of its own, distinct from the importing cluster's live ClusterConfig,
+// and returns the validated snapshot. A leaf's exported snapshot reflects
+// that leaf's settings, not the importing (root) cluster's, so Import must
+// never write it to the local general ClusterConfig key.
+func (i *TrustedClusterConfigImporter) Import(ctx context.Context, snapshot []byte, jws string, leafJWKS jwt.JWKS) (services.ClusterConfig, error) {
+	if err := jwt.VerifyJWKS(leafJWKS, snapshot, jws); err != nil {
+		return nil, trace.Wrap(err, "trusted cluster configuration snapshot failed JWKS verification")
+	}
+	cc, err := services.GetClusterConfigMarshaler().Unmarshal(snapshot)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	_, err = i.Put(ctx, backend.Item{
+		Key:   backend.Key(clusterConfigPrefix, importedPrefix, generalPrefix),
+		Value: snapshot,
+		ID:    cc.GetResourceID(),
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return cc, nil
+}
+
+// GetImportedClusterConfig returns the most recently imported trusted
+// cluster configuration snapshot, if any.
+func (i *TrustedClusterConfigImporter) GetImportedClusterConfig(ctx context.Context) (services.ClusterConfig, error) {
+	item, err := i.Get(ctx, backend.Key(clusterConfigPrefix, importedPrefix, generalPrefix))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no trusted cluster configuration has been imported")
+		}
+		return nil, trace.Wrap(err)
+	}
+	return services.GetClusterConfigMarshaler().Unmarshal(item.Value, services.WithResourceID(item.ID))
+}
+
+const (
+	signaturesPrefix = "signatures"
+	importedPrefix   = "imported"
+)
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/backend/memory"
+	"github.com/gravitational/teleport/lib/jwt"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// stubClusterConfigSigner is a ClusterConfigSigner backed by an in-memory
+// jwt.Key, for tests that don't need a real cluster JWT CA.
+type stubClusterConfigSigner struct {
+	key *jwt.Key
+}
+
+func newStubClusterConfigSigner(t *testing.T) *stubClusterConfigSigner {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := jwt.New(jwt.Config{ClusterName: "test-cluster", PrivateKey: priv})
+	require.NoError(t, err)
+	return &stubClusterConfigSigner{key: key}
+}
+
+func (s *stubClusterConfigSigner) SignSnapshot(data []byte) (string, error) {
+	return s.key.SignDetached(data)
+}
+
+func (s *stubClusterConfigSigner) VerifySnapshot(data []byte, jws string) error {
+	return s.key.VerifyDetached(data, jws)
+}
+
+func TestUpdateClusterConfigReSignsOnCAS(t *testing.T) {
+	mem, err := memory.New(memory.Config{})
+	require.NoError(t, err)
+
+	signer := newStubClusterConfigSigner(t)
+	svc := NewClusterConfigurationService(mem, WithClusterConfigSigner(signer))
+
+	cc, err := services.NewClusterConfig(services.ClusterConfigSpecV3{})
+	require.NoError(t, err)
+	require.NoError(t, svc.SetClusterConfig(cc))
+
+	_, err = svc.VerifySignedClusterConfig(context.Background())
+	require.NoError(t, err, "signature produced by SetClusterConfig should verify")
+
+	updated, err := services.NewClusterConfig(services.ClusterConfigSpecV3{
+		SessionRecording: "proxy",
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.UpdateClusterConfig(context.Background(), updated))
+
+	_, err = svc.VerifySignedClusterConfig(context.Background())
+	require.NoError(t, err, "signature must be refreshed after a CAS-based update, not left covering the stale value")
+}
+
+func TestDeleteClusterConfigRemovesOrphanedSignature(t *testing.T) {
+	mem, err := memory.New(memory.Config{})
+	require.NoError(t, err)
+
+	signer := newStubClusterConfigSigner(t)
+	svc := NewClusterConfigurationService(mem, WithClusterConfigSigner(signer))
+
+	cc, err := services.NewClusterConfig(services.ClusterConfigSpecV3{})
+	require.NoError(t, err)
+	require.NoError(t, svc.SetClusterConfig(cc))
+
+	sigKey := backend.Key(clusterConfigPrefix, signaturesPrefix, generalPrefix)
+	_, err = mem.Get(context.Background(), sigKey)
+	require.NoError(t, err, "signature should exist after SetClusterConfig")
+
+	require.NoError(t, svc.DeleteClusterConfig())
+
+	_, err = mem.Get(context.Background(), sigKey)
+	require.True(t, trace.IsNotFound(err), "signature must be deleted alongside the resource, got %T: %v", err, err)
+}
+
+func TestImportDoesNotOverwriteLocalClusterConfig(t *testing.T) {
+	mem, err := memory.New(memory.Config{})
+	require.NoError(t, err)
+
+	local := NewClusterConfigurationService(mem)
+	localConfig, err := services.NewClusterConfig(services.ClusterConfigSpecV3{
+		SessionRecording: "node",
+	})
+	require.NoError(t, err)
+	require.NoError(t, local.SetClusterConfig(localConfig))
+
+	leafPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	leafKey, err := jwt.New(jwt.Config{ClusterName: "leaf-cluster", PrivateKey: leafPriv})
+	require.NoError(t, err)
+	leafJWKS := jwt.JWKS{Keys: []jose.JSONWebKey{{Key: leafKey.PublicKey()}}}
+
+	leafConfig, err := services.NewClusterConfig(services.ClusterConfigSpecV3{
+		SessionRecording: "proxy",
+	})
+	require.NoError(t, err)
+	snapshot, err := services.GetClusterConfigMarshaler().Marshal(leafConfig)
+	require.NoError(t, err)
+	jws, err := leafKey.SignDetached(snapshot)
+	require.NoError(t, err)
+
+	importer := NewTrustedClusterConfigImporter(local)
+	imported, err := importer.Import(context.Background(), snapshot, jws, leafJWKS)
+	require.NoError(t, err)
+	require.Equal(t, "proxy", imported.GetSessionRecording())
+
+	// The importing cluster's own live ClusterConfig must be untouched.
+	got, err := local.GetClusterConfig()
+	require.NoError(t, err)
+	require.Equal(t, "node", got.GetSessionRecording())
+
+	fromStore, err := importer.GetImportedClusterConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "proxy", fromStore.GetSessionRecording())
+}
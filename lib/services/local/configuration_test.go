@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/backend/memory"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+// conflictingBackend wraps a backend.Backend and, the first time
+// CompareAndSwap is called, runs trigger to simulate a write from another
+// client landing between the caller's read and its CAS attempt.
+type conflictingBackend struct {
+	backend.Backend
+	trigger   func() error
+	triggered bool
+}
+
+func (b *conflictingBackend) CompareAndSwap(ctx context.Context, expected, replaceWith backend.Item) (*backend.Lease, error) {
+	if !b.triggered {
+		b.triggered = true
+		if err := b.trigger(); err != nil {
+			return nil, err
+		}
+	}
+	return b.Backend.CompareAndSwap(ctx, expected, replaceWith)
+}
+
+func TestUpdateAuthPreferenceDetectsConflict(t *testing.T) {
+	mem, err := memory.New(memory.Config{})
+	require.NoError(t, err)
+
+	initial, err := services.NewAuthPreference(services.AuthPreferenceSpecV2{
+		Type:         "local",
+		SecondFactor: "off",
+	})
+	require.NoError(t, err)
+
+	base := NewClusterConfigurationService(mem)
+	require.NoError(t, base.SetAuthPreference(initial))
+
+	conflicting := &conflictingBackend{
+		Backend: mem,
+		trigger: func() error {
+			concurrent, err := services.NewAuthPreference(services.AuthPreferenceSpecV2{
+				Type:         "local",
+				SecondFactor: "otp",
+			})
+			if err != nil {
+				return err
+			}
+			return base.SetAuthPreference(concurrent)
+		},
+	}
+	svc := NewClusterConfigurationService(conflicting)
+
+	update, err := services.NewAuthPreference(services.AuthPreferenceSpecV2{
+		Type:         "local",
+		SecondFactor: "u2f",
+	})
+	require.NoError(t, err)
+
+	err = svc.UpdateAuthPreference(context.Background(), update)
+	require.Error(t, err)
+	require.True(t, trace.IsCompareFailed(err), "expected a compare-failed error, got %T: %v", err, err)
+
+	// The conflicting write from the "other client" must have stuck.
+	got, err := base.GetAuthPreference()
+	require.NoError(t, err)
+	require.Equal(t, "otp", got.GetSecondFactor())
+}
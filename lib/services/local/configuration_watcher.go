@@ -0,0 +1,229 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/gravitational/trace"
+)
+
+// ClusterConfigEvent is implemented by all the typed events delivered by a
+// ClusterConfigWatcher.
+type ClusterConfigEvent interface {
+	// Op is the kind of change that occurred, e.g. backend.OpPut or
+	// backend.OpDelete.
+	Op() backend.Op
+}
+
+type baseEvent struct {
+	op backend.Op
+}
+
+// Op is the kind of change that occurred.
+func (e baseEvent) Op() backend.Op {
+	return e.op
+}
+
+// ClusterNameEvent is emitted when services.ClusterName changes.
+type ClusterNameEvent struct {
+	baseEvent
+	// ClusterName is the updated resource. It is nil for delete events.
+	ClusterName services.ClusterName
+}
+
+// StaticTokensEvent is emitted when services.StaticTokens changes.
+type StaticTokensEvent struct {
+	baseEvent
+	// StaticTokens is the updated resource. It is nil for delete events.
+	StaticTokens services.StaticTokens
+}
+
+// AuthPreferenceEvent is emitted when services.AuthPreference changes.
+type AuthPreferenceEvent struct {
+	baseEvent
+	// AuthPreference is the updated resource. It is nil for delete events.
+	AuthPreference services.AuthPreference
+}
+
+// ClusterConfigResourceEvent is emitted when services.ClusterConfig changes.
+type ClusterConfigResourceEvent struct {
+	baseEvent
+	// ClusterConfig is the updated resource. It is nil for delete events.
+	ClusterConfig services.ClusterConfig
+}
+
+// ClusterConfigWatcher delivers typed change events for the resources
+// managed by ClusterConfigurationService, so consumers like proxies and
+// nodes can react to configuration changes instead of polling.
+type ClusterConfigWatcher struct {
+	backendWatcher backend.Watcher
+	eventsC        chan ClusterConfigEvent
+	cancel         context.CancelFunc
+}
+
+// NewClusterConfigWatcher subscribes to changes affecting cluster name,
+// static tokens, auth preference, and cluster config, and returns a
+// ClusterConfigWatcher that delivers typed events as they occur.
+func (s *ClusterConfigurationService) NewClusterConfigWatcher(ctx context.Context) (*ClusterConfigWatcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	w, err := s.NewWatcher(ctx, backend.Watch{
+		Name: "cluster-config-watcher",
+		Prefixes: [][]byte{
+			backend.Key(clusterConfigPrefix),
+			backend.Key(authPrefix, preferencePrefix, generalPrefix),
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, trace.Wrap(err)
+	}
+
+	ccw := &ClusterConfigWatcher{
+		backendWatcher: w,
+		eventsC:        make(chan ClusterConfigEvent),
+		cancel:         cancel,
+	}
+	go ccw.forward()
+	return ccw, nil
+}
+
+// Events returns the channel typed cluster configuration events are
+// delivered on.
+func (w *ClusterConfigWatcher) Events() <-chan ClusterConfigEvent {
+	return w.eventsC
+}
+
+// Done is closed when the watcher has stopped, either because it was closed
+// or because the underlying backend watcher failed.
+func (w *ClusterConfigWatcher) Done() <-chan struct{} {
+	return w.backendWatcher.Done()
+}
+
+// Close stops the watcher and releases the underlying backend watcher.
+func (w *ClusterConfigWatcher) Close() error {
+	w.cancel()
+	return w.backendWatcher.Close()
+}
+
+func (w *ClusterConfigWatcher) forward() {
+	for {
+		select {
+		case event := <-w.backendWatcher.Events():
+			typed, err := decodeClusterConfigEvent(event)
+			if err != nil {
+				// Skip events we don't recognize or can't decode rather
+				// than tearing down the whole watcher.
+				continue
+			}
+			select {
+			case w.eventsC <- typed:
+			case <-w.backendWatcher.Done():
+				return
+			}
+		case <-w.backendWatcher.Done():
+			return
+		}
+	}
+}
+
+// Cluster configuration event kinds, as identified by matchClusterConfigKey.
+const (
+	eventKindClusterName    = "cluster_name"
+	eventKindStaticTokens   = "static_tokens"
+	eventKindAuthPreference = "auth_preference"
+	eventKindClusterConfig  = "cluster_config"
+)
+
+// matchClusterConfigKey identifies which cluster configuration resource a
+// raw backend key belongs to, by suffix, or returns "" if the key is not
+// one ClusterConfigWatcher understands.
+func matchClusterConfigKey(key string) string {
+	switch {
+	case strings.HasSuffix(key, string(backend.Key(clusterConfigPrefix, namePrefix))):
+		return eventKindClusterName
+	case strings.HasSuffix(key, string(backend.Key(clusterConfigPrefix, staticTokensPrefix))):
+		return eventKindStaticTokens
+	case strings.HasSuffix(key, string(backend.Key(authPrefix, preferencePrefix, generalPrefix))):
+		return eventKindAuthPreference
+	case strings.HasSuffix(key, string(backend.Key(clusterConfigPrefix, generalPrefix))):
+		return eventKindClusterConfig
+	default:
+		return ""
+	}
+}
+
+// decodeClusterConfigEvent decodes a raw backend.Event into one of the typed
+// ClusterConfigEvent variants based on its key.
+func decodeClusterConfigEvent(event backend.Event) (ClusterConfigEvent, error) {
+	key := string(event.Item.Key)
+	base := baseEvent{op: event.Type}
+
+	switch matchClusterConfigKey(key) {
+	case eventKindClusterName:
+		if event.Type == backend.OpDelete {
+			return ClusterNameEvent{baseEvent: base}, nil
+		}
+		cn, err := services.GetClusterNameMarshaler().Unmarshal(event.Item.Value,
+			services.WithResourceID(event.Item.ID))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return ClusterNameEvent{baseEvent: base, ClusterName: cn}, nil
+
+	case eventKindStaticTokens:
+		if event.Type == backend.OpDelete {
+			return StaticTokensEvent{baseEvent: base}, nil
+		}
+		st, err := services.GetStaticTokensMarshaler().Unmarshal(event.Item.Value,
+			services.WithResourceID(event.Item.ID), services.WithExpires(event.Item.Expires))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return StaticTokensEvent{baseEvent: base, StaticTokens: st}, nil
+
+	case eventKindAuthPreference:
+		if event.Type == backend.OpDelete {
+			return AuthPreferenceEvent{baseEvent: base}, nil
+		}
+		ap, err := services.GetAuthPreferenceMarshaler().Unmarshal(event.Item.Value,
+			services.WithResourceID(event.Item.ID), services.WithExpires(event.Item.Expires))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return AuthPreferenceEvent{baseEvent: base, AuthPreference: ap}, nil
+
+	case eventKindClusterConfig:
+		if event.Type == backend.OpDelete {
+			return ClusterConfigResourceEvent{baseEvent: base}, nil
+		}
+		cc, err := services.GetClusterConfigMarshaler().Unmarshal(event.Item.Value,
+			services.WithResourceID(event.Item.ID), services.WithExpires(event.Item.Expires))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return ClusterConfigResourceEvent{baseEvent: base, ClusterConfig: cc}, nil
+
+	default:
+		return nil, trace.NotFound("unrecognized cluster configuration key %q", key)
+	}
+}
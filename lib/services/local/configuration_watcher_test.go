@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"testing"
+
+	"github.com/gravitational/teleport/lib/backend"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchClusterConfigKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{
+			name: "cluster name",
+			key:  string(backend.Key(clusterConfigPrefix, namePrefix)),
+			want: eventKindClusterName,
+		},
+		{
+			name: "static tokens",
+			key:  string(backend.Key(clusterConfigPrefix, staticTokensPrefix)),
+			want: eventKindStaticTokens,
+		},
+		{
+			name: "auth preference",
+			key:  string(backend.Key(authPrefix, preferencePrefix, generalPrefix)),
+			want: eventKindAuthPreference,
+		},
+		{
+			name: "cluster config",
+			key:  string(backend.Key(clusterConfigPrefix, generalPrefix)),
+			want: eventKindClusterConfig,
+		},
+		{
+			name: "cluster config signatures key is not mistaken for cluster config",
+			key:  string(backend.Key(clusterConfigPrefix, signaturesPrefix, generalPrefix)),
+			want: "",
+		},
+		{
+			name: "registry auth config is unrelated",
+			key:  string(backend.Key(registryAuthPrefix, generalPrefix)),
+			want: "",
+		},
+		{
+			name: "unrecognized key",
+			key:  "something/else",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, matchClusterConfigKey(tt.key))
+		})
+	}
+}
+
+func TestDecodeClusterConfigEventDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want ClusterConfigEvent
+	}{
+		{
+			name: "cluster name delete",
+			key:  string(backend.Key(clusterConfigPrefix, namePrefix)),
+			want: ClusterNameEvent{baseEvent: baseEvent{op: backend.OpDelete}},
+		},
+		{
+			name: "static tokens delete",
+			key:  string(backend.Key(clusterConfigPrefix, staticTokensPrefix)),
+			want: StaticTokensEvent{baseEvent: baseEvent{op: backend.OpDelete}},
+		},
+		{
+			name: "auth preference delete",
+			key:  string(backend.Key(authPrefix, preferencePrefix, generalPrefix)),
+			want: AuthPreferenceEvent{baseEvent: baseEvent{op: backend.OpDelete}},
+		},
+		{
+			name: "cluster config delete",
+			key:  string(backend.Key(clusterConfigPrefix, generalPrefix)),
+			want: ClusterConfigResourceEvent{baseEvent: baseEvent{op: backend.OpDelete}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := decodeClusterConfigEvent(backend.Event{
+				Type: backend.OpDelete,
+				Item: backend.Item{Key: []byte(tt.key)},
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, event)
+			require.Equal(t, backend.OpDelete, event.Op())
+		})
+	}
+}
+
+func TestDecodeClusterConfigEventUnrecognized(t *testing.T) {
+	_, err := decodeClusterConfigEvent(backend.Event{
+		Type: backend.OpPut,
+		Item: backend.Item{Key: []byte("something/else")},
+	})
+	require.Error(t, err)
+}
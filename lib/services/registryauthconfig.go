@@ -0,0 +1,262 @@
+/*
+Copyright 2021 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"time"
+
+	"github.com/gravitational/teleport/lib/defaults"
+	"github.com/gravitational/teleport/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// RegistryAuthConfig defines how Teleport issues Docker Registry v2 bearer
+// tokens on behalf of authenticated users.
+type RegistryAuthConfig interface {
+	// Resource provides common resource methods.
+	Resource
+
+	// GetIssuer returns the issuer URL advertised in minted tokens.
+	GetIssuer() string
+	// SetIssuer sets the issuer URL advertised in minted tokens.
+	SetIssuer(string)
+
+	// GetService returns the registry service name tokens are scoped to.
+	GetService() string
+	// SetService sets the registry service name tokens are scoped to.
+	SetService(string)
+
+	// GetSigningCA returns the name of the CA used to sign minted tokens.
+	GetSigningCA() string
+	// SetSigningCA sets the name of the CA used to sign minted tokens.
+	SetSigningCA(string)
+
+	// GetTokenTTL returns how long minted bearer tokens remain valid.
+	GetTokenTTL() time.Duration
+	// SetTokenTTL sets how long minted bearer tokens remain valid.
+	SetTokenTTL(time.Duration)
+
+	// CheckAndSetDefaults checks and sets default values for missing fields.
+	CheckAndSetDefaults() error
+}
+
+// NewRegistryAuthConfig creates a new RegistryAuthConfig with the given spec.
+func NewRegistryAuthConfig(spec RegistryAuthConfigSpecV1) (RegistryAuthConfig, error) {
+	rac := RegistryAuthConfigV1{
+		Kind:    KindRegistryAuthConfig,
+		Version: V1,
+		Metadata: Metadata{
+			Name:      MetaNameRegistryAuthConfig,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+	if err := rac.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &rac, nil
+}
+
+// RegistryAuthConfigV1 implements RegistryAuthConfig.
+type RegistryAuthConfigV1 struct {
+	// Kind is a resource kind, always "registry_auth".
+	Kind string `json:"kind"`
+	// Version is a resource version.
+	Version string `json:"version"`
+	// Metadata is a resource metadata.
+	Metadata Metadata `json:"metadata"`
+	// Spec is a registry auth config specification.
+	Spec RegistryAuthConfigSpecV1 `json:"spec"`
+}
+
+// RegistryAuthConfigSpecV1 is the actual data we care about for the
+// RegistryAuthConfigV1 resource.
+type RegistryAuthConfigSpecV1 struct {
+	// Issuer is the issuer URL advertised in minted bearer tokens.
+	Issuer string `json:"issuer"`
+	// Service is the registry service name tokens are scoped to.
+	Service string `json:"service"`
+	// SigningCA is the name of the CA used to sign minted tokens.
+	SigningCA string `json:"signing_ca"`
+	// TokenTTL is how long minted bearer tokens remain valid.
+	TokenTTL time.Duration `json:"token_ttl"`
+}
+
+// GetName returns the name of the resource.
+func (c *RegistryAuthConfigV1) GetName() string {
+	return c.Metadata.Name
+}
+
+// SetName sets the name of the resource.
+func (c *RegistryAuthConfigV1) SetName(name string) {
+	c.Metadata.Name = name
+}
+
+// GetMetadata returns the resource metadata.
+func (c *RegistryAuthConfigV1) GetMetadata() Metadata {
+	return c.Metadata
+}
+
+// Expiry returns the resource expiry time.
+func (c *RegistryAuthConfigV1) Expiry() time.Time {
+	return c.Metadata.Expiry()
+}
+
+// SetExpiry sets the resource expiry time.
+func (c *RegistryAuthConfigV1) SetExpiry(t time.Time) {
+	c.Metadata.SetExpiry(t)
+}
+
+// SetTTL sets the resource TTL relative to the provided clock.
+func (c *RegistryAuthConfigV1) SetTTL(clock Clock, ttl time.Duration) {
+	c.Metadata.SetTTL(clock, ttl)
+}
+
+// GetResourceID returns the resource ID.
+func (c *RegistryAuthConfigV1) GetResourceID() int64 {
+	return c.Metadata.ID
+}
+
+// SetResourceID sets the resource ID.
+func (c *RegistryAuthConfigV1) SetResourceID(id int64) {
+	c.Metadata.ID = id
+}
+
+// GetIssuer returns the issuer URL advertised in minted tokens.
+func (c *RegistryAuthConfigV1) GetIssuer() string {
+	return c.Spec.Issuer
+}
+
+// SetIssuer sets the issuer URL advertised in minted tokens.
+func (c *RegistryAuthConfigV1) SetIssuer(issuer string) {
+	c.Spec.Issuer = issuer
+}
+
+// GetService returns the registry service name tokens are scoped to.
+func (c *RegistryAuthConfigV1) GetService() string {
+	return c.Spec.Service
+}
+
+// SetService sets the registry service name tokens are scoped to.
+func (c *RegistryAuthConfigV1) SetService(service string) {
+	c.Spec.Service = service
+}
+
+// GetSigningCA returns the name of the CA used to sign minted tokens.
+func (c *RegistryAuthConfigV1) GetSigningCA() string {
+	return c.Spec.SigningCA
+}
+
+// SetSigningCA sets the name of the CA used to sign minted tokens.
+func (c *RegistryAuthConfigV1) SetSigningCA(ca string) {
+	c.Spec.SigningCA = ca
+}
+
+// GetTokenTTL returns how long minted bearer tokens remain valid.
+func (c *RegistryAuthConfigV1) GetTokenTTL() time.Duration {
+	return c.Spec.TokenTTL
+}
+
+// SetTokenTTL sets how long minted bearer tokens remain valid.
+func (c *RegistryAuthConfigV1) SetTokenTTL(ttl time.Duration) {
+	c.Spec.TokenTTL = ttl
+}
+
+// CheckAndSetDefaults checks and sets default values for missing fields.
+func (c *RegistryAuthConfigV1) CheckAndSetDefaults() error {
+	if c.Metadata.Name == "" {
+		c.Metadata.Name = MetaNameRegistryAuthConfig
+	}
+	if c.Version == "" {
+		c.Version = V1
+	}
+	if c.Spec.Issuer == "" {
+		return trace.BadParameter("registry auth config: issuer is required")
+	}
+	if c.Spec.Service == "" {
+		return trace.BadParameter("registry auth config: service is required")
+	}
+	if c.Spec.SigningCA == "" {
+		return trace.BadParameter("registry auth config: signing_ca is required")
+	}
+	if c.Spec.TokenTTL == 0 {
+		c.Spec.TokenTTL = defaults.RegistryAuthTokenTTL
+	}
+	return nil
+}
+
+const (
+	// KindRegistryAuthConfig is the registry auth config resource kind.
+	KindRegistryAuthConfig = "registry_auth"
+	// MetaNameRegistryAuthConfig is the exact name of the singleton resource
+	// holding the registry auth config.
+	MetaNameRegistryAuthConfig = "registry-auth-config"
+)
+
+// RegistryAuthConfigMarshaler implements marshal/unmarshal of RegistryAuthConfig
+// implementations, adding support for extended versions.
+type RegistryAuthConfigMarshaler interface {
+	Marshal(c RegistryAuthConfig, opts ...MarshalOption) ([]byte, error)
+	Unmarshal(bytes []byte, opts ...MarshalOption) (RegistryAuthConfig, error)
+}
+
+type teleportRegistryAuthConfigMarshaler struct{}
+
+// Marshal marshals the RegistryAuthConfig resource to JSON.
+func (*teleportRegistryAuthConfigMarshaler) Marshal(c RegistryAuthConfig, opts ...MarshalOption) ([]byte, error) {
+	return utils.FastMarshal(c)
+}
+
+// Unmarshal unmarshals the RegistryAuthConfig resource from JSON.
+func (*teleportRegistryAuthConfigMarshaler) Unmarshal(bytes []byte, opts ...MarshalOption) (RegistryAuthConfig, error) {
+	var rac RegistryAuthConfigV1
+	if err := utils.FastUnmarshal(bytes, &rac); err != nil {
+		return nil, trace.BadParameter(err.Error())
+	}
+	cfg, err := CollectOptions(opts)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := rac.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if cfg.ID != 0 {
+		rac.SetResourceID(cfg.ID)
+	}
+	if !cfg.Expires.IsZero() {
+		rac.SetExpiry(cfg.Expires)
+	}
+	return &rac, nil
+}
+
+var registryAuthConfigMarshaler RegistryAuthConfigMarshaler = &teleportRegistryAuthConfigMarshaler{}
+
+// SetRegistryAuthConfigMarshaler sets the global RegistryAuthConfigMarshaler.
+func SetRegistryAuthConfigMarshaler(m RegistryAuthConfigMarshaler) {
+	marshalerMutex.Lock()
+	defer marshalerMutex.Unlock()
+	registryAuthConfigMarshaler = m
+}
+
+// GetRegistryAuthConfigMarshaler returns the currently registered
+// RegistryAuthConfigMarshaler.
+func GetRegistryAuthConfigMarshaler() RegistryAuthConfigMarshaler {
+	marshalerMutex.Lock()
+	defer marshalerMutex.Unlock()
+	return registryAuthConfigMarshaler
+}